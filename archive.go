@@ -0,0 +1,249 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ArchiveKind identifies a supported bundle format, detected from the
+// input path's extension.
+type ArchiveKind int
+
+const (
+	archiveNone   ArchiveKind = iota
+	archiveZip                // .zip, .cbz (comic-book zip; same container)
+	archiveTarGz              // .tar.gz, .tgz
+	archiveTarBz2             // .tar.bz2, .tbz2
+)
+
+// DetectArchiveKind inspects path's extension and reports whether it names
+// a bundle this tool knows how to walk, so callers can fall back to plain
+// directory handling for anything else.
+func DetectArchiveKind(path string) ArchiveKind {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".zip"), strings.HasSuffix(lower, ".cbz"):
+		return archiveZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGz
+	case strings.HasSuffix(lower, ".tar.bz2"), strings.HasSuffix(lower, ".tbz2"):
+		return archiveTarBz2
+	default:
+		return archiveNone
+	}
+}
+
+func isPDFName(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".pdf")
+}
+
+// safeJoin resolves name (an archive entry path, using forward slashes per
+// the zip/tar spec) against destDir and rejects it if the result would
+// escape destDir — a zip-slip/tar-slip entry like "../../etc/passwd" or an
+// absolute path. destDir itself is returned unescaped.
+func safeJoin(destDir, name string) (string, error) {
+	dest := filepath.Join(destDir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(destDir, dest)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive: entry %q escapes destination directory", name)
+	}
+	return dest, nil
+}
+
+// ExtractArchive unpacks every PDF contained in the archive at path into
+// destDir, preserving the archive's internal directory layout so the
+// resulting tree can be walked with findAllPDFs like any other input
+// directory.
+func ExtractArchive(path string, kind ArchiveKind, destDir string) error {
+	switch kind {
+	case archiveZip:
+		return extractZip(path, destDir)
+	case archiveTarGz:
+		return extractTar(path, destDir, func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) })
+	case archiveTarBz2:
+		return extractTar(path, destDir, func(r io.Reader) (io.Reader, error) { return bzip2.NewReader(r), nil })
+	default:
+		return fmt.Errorf("archive: unsupported kind for %s", path)
+	}
+}
+
+func extractZip(path, destDir string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("archive: open %s: %w", path, err)
+	}
+	defer r.Close()
+
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || !isPDFName(f.Name) {
+			continue
+		}
+		if err := extractZipEntry(f, destDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractZipEntry(f *zip.File, destDir string) error {
+	dest, err := safeJoin(destDir, f.Name)
+	if err != nil {
+		return err
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("archive: read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, rc)
+	return err
+}
+
+// decompressorFunc wraps the raw archive stream in whatever compression
+// the tar container uses (gzip or bzip2).
+type decompressorFunc func(io.Reader) (io.Reader, error)
+
+func extractTar(path, destDir string, decompress decompressorFunc) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("archive: open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	decompressed, err := decompress(f)
+	if err != nil {
+		return fmt.Errorf("archive: decompress %s: %w", path, err)
+	}
+	tr := tar.NewReader(decompressed)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("archive: read %s: %w", path, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || !isPDFName(hdr.Name) {
+			continue
+		}
+		dest, err := safeJoin(destDir, hdr.Name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+			return err
+		}
+		out, err := os.Create(dest)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, tr); err != nil {
+			out.Close()
+			return err
+		}
+		out.Close()
+	}
+}
+
+// PackArchive walks srcDir and writes every file it finds into a new
+// archive at destPath. tar.bz2 inputs are repacked as tar.gz: Go's
+// standard library can only read bzip2, not write it, so there is no
+// stdlib-only way to reproduce the original compression.
+func PackArchive(srcDir string, kind ArchiveKind, destPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destPath), os.ModePerm); err != nil {
+		return fmt.Errorf("archive: %w", err)
+	}
+	switch kind {
+	case archiveZip:
+		return packZip(srcDir, destPath)
+	case archiveTarGz, archiveTarBz2:
+		return packTarGz(srcDir, destPath)
+	default:
+		return fmt.Errorf("archive: unsupported kind for %s", destPath)
+	}
+}
+
+func packZip(srcDir, destPath string) error {
+	out, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+	defer zw.Close()
+
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(w, in)
+		return err
+	})
+}
+
+func packTarGz(srcDir, destPath string) error {
+	out, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	gw := gzip.NewWriter(out)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+}
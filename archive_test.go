@@ -0,0 +1,170 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTarGz(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range entries {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractTarRejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "evil.tar.gz")
+	escapeTarget := filepath.Join(filepath.Dir(dir), "tarslip_poc.pdf")
+	os.Remove(escapeTarget)
+	defer os.Remove(escapeTarget)
+	writeTarGz(t, archivePath, map[string]string{
+		"../tarslip_poc.pdf": "%PDF-1.4\n%%EOF",
+	})
+
+	destDir := filepath.Join(dir, "extracted")
+	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractArchive(archivePath, archiveTarGz, destDir); err == nil {
+		t.Fatal("expected extraction of a path-traversal entry to fail, got nil error")
+	}
+	if _, err := os.Stat(escapeTarget); err == nil {
+		t.Fatal("path-traversal entry escaped destDir")
+	}
+}
+
+func TestExtractPackTarGzRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "in.tar.gz")
+	writeTarGz(t, archivePath, map[string]string{
+		"doc.pdf":        "%PDF-1.4\n%%EOF",
+		"sub/nested.pdf": "%PDF-1.4\n%%EOF",
+		"readme.txt":     "not a pdf, should be skipped",
+	})
+
+	destDir := filepath.Join(dir, "extracted")
+	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractArchive(archivePath, archiveTarGz, destDir); err != nil {
+		t.Fatal(err)
+	}
+
+	pdfs, err := findAllPDFs(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pdfs) != 2 {
+		t.Fatalf("expected 2 extracted PDFs, got %d: %v", len(pdfs), pdfs)
+	}
+
+	outPath := filepath.Join(dir, "out.tar.gz")
+	if err := PackArchive(destDir, archiveTarGz, outPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outPath); err != nil {
+		t.Fatalf("packed archive missing: %v", err)
+	}
+}
+
+func TestExtractZipRejectsPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "evil.zip")
+	escapeTarget := filepath.Join(filepath.Dir(dir), "zipslip_poc.pdf")
+	os.Remove(escapeTarget)
+	defer os.Remove(escapeTarget)
+	writeZip(t, archivePath, map[string]string{
+		"../zipslip_poc.pdf": "%PDF-1.4\n%%EOF",
+	})
+
+	destDir := filepath.Join(dir, "extracted")
+	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractArchive(archivePath, archiveZip, destDir); err == nil {
+		t.Fatal("expected extraction of a path-traversal entry to fail, got nil error")
+	}
+	if _, err := os.Stat(escapeTarget); err == nil {
+		t.Fatal("path-traversal entry escaped destDir")
+	}
+}
+
+func TestExtractPackZipRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	archivePath := filepath.Join(dir, "in.zip")
+	writeZip(t, archivePath, map[string]string{
+		"doc.pdf":        "%PDF-1.4\n%%EOF",
+		"sub/nested.pdf": "%PDF-1.4\n%%EOF",
+		"readme.txt":     "not a pdf, should be skipped",
+	})
+
+	destDir := filepath.Join(dir, "extracted")
+	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractArchive(archivePath, archiveZip, destDir); err != nil {
+		t.Fatal(err)
+	}
+
+	pdfs, err := findAllPDFs(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pdfs) != 2 {
+		t.Fatalf("expected 2 extracted PDFs, got %d: %v", len(pdfs), pdfs)
+	}
+
+	outPath := filepath.Join(dir, "out.zip")
+	if err := PackArchive(destDir, archiveZip, outPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(outPath); err != nil {
+		t.Fatalf("packed archive missing: %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Quality is a canonical, backend-independent compression preset. Each
+// Compressor implementation translates it into whatever flags its own
+// binary understands.
+type Quality string
+
+const (
+	QualityScreen   Quality = "screen"
+	QualityEbook    Quality = "ebook"
+	QualityPrinter  Quality = "printer"
+	QualityPrepress Quality = "prepress"
+	QualityDefault  Quality = "default"
+)
+
+// Compressor recompresses a single PDF. Implementations are responsible for
+// locating their own binary and translating Quality into backend-specific
+// arguments.
+type Compressor interface {
+	// Name identifies the backend for -backend selection and log output.
+	Name() string
+	// Compress rewrites input into output at the given quality preset.
+	Compress(input, output string, quality Quality) CompressResult
+}
+
+// CompressResult is what a Compressor reports for a single file, with
+// enough diagnostic detail (exit code, stderr) for the caller to build a
+// FailedItem without re-running the backend.
+type CompressResult struct {
+	OK       bool
+	OrigSize int64
+	CompSize int64
+	Path     string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+// runBackend execs bin with args, capturing stderr and the process exit
+// code for diagnostics. Callers still need to fill in OrigSize/CompSize/OK
+// once they've stat'd the output.
+func runBackend(bin string, args []string) (stderr string, exitCode int, err error) {
+	cmd := exec.Command(bin, args...)
+	var stderrBuf strings.Builder
+	cmd.Stderr = &stderrBuf
+	err = cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		exitCode = exitErr.ExitCode()
+	}
+	return stderrBuf.String(), exitCode, err
+}
+
+// lookPathAny returns the first candidate found on PATH, or an error
+// listing everything that was tried.
+func lookPathAny(candidates []string) (string, error) {
+	for _, name := range candidates {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("none of %v found in PATH", candidates)
+}
+
+// GhostscriptCompressor drives Ghostscript's pdfwrite device. It is the
+// default backend and the only one with first-class quality presets.
+type GhostscriptCompressor struct {
+	bin string
+}
+
+// gsCandidates returns the Ghostscript executable names to probe for,
+// matching the platform's usual packaging: gswin64c/gswin32c on Windows,
+// plain gs everywhere else.
+func gsCandidates() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"gswin64c", "gswin32c", "gs"}
+	}
+	return []string{"gs"}
+}
+
+// NewGhostscriptCompressor locates a Ghostscript binary on PATH, trying the
+// platform's known executable names in order.
+func NewGhostscriptCompressor() (*GhostscriptCompressor, error) {
+	bin, err := lookPathAny(gsCandidates())
+	if err != nil {
+		return nil, fmt.Errorf("ghostscript: %w", err)
+	}
+	return &GhostscriptCompressor{bin: bin}, nil
+}
+
+func (c *GhostscriptCompressor) Name() string { return "ghostscript" }
+
+// gsPreset translates a canonical Quality into a Ghostscript -dPDFSETTINGS
+// value. The names happen to match 1:1, but the mapping is explicit so
+// other backends can diverge from Ghostscript's vocabulary.
+func gsPreset(quality Quality) string {
+	switch quality {
+	case QualityScreen:
+		return "/screen"
+	case QualityPrinter:
+		return "/printer"
+	case QualityPrepress:
+		return "/prepress"
+	case QualityDefault:
+		return "/default"
+	case QualityEbook:
+		return "/ebook"
+	default:
+		return "/ebook"
+	}
+}
+
+func (c *GhostscriptCompressor) Compress(input, output string, quality Quality) CompressResult {
+	os.MkdirAll(filepath.Dir(output), os.ModePerm)
+	args := []string{
+		"-sDEVICE=pdfwrite",
+		"-dCompatibilityLevel=1.4",
+		fmt.Sprintf("-dPDFSETTINGS=%s", gsPreset(quality)),
+		"-dNOPAUSE",
+		"-dQUIET",
+		"-dBATCH",
+		fmt.Sprintf("-sOutputFile=%s", output),
+		input,
+	}
+	origInfo, err := os.Stat(input)
+	if err != nil {
+		return CompressResult{Path: input, Err: err}
+	}
+	stderr, exitCode, runErr := runBackend(c.bin, args)
+	if runErr != nil {
+		return CompressResult{Path: input, ExitCode: exitCode, Stderr: stderr, Err: runErr}
+	}
+	compInfo, err := os.Stat(output)
+	if err != nil {
+		return CompressResult{Path: input, Err: err}
+	}
+	return CompressResult{OK: true, OrigSize: origInfo.Size(), CompSize: compInfo.Size(), Path: input}
+}
+
+// QpdfCompressor drives qpdf's stream-compression and object-stream
+// optimizations. It has no notion of image downsampling, so all quality
+// presets above "screen" behave the same (linearized, compressed streams).
+type QpdfCompressor struct {
+	bin string
+}
+
+func qpdfCandidates() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"qpdf.exe", "qpdf"}
+	}
+	return []string{"qpdf"}
+}
+
+// NewQpdfCompressor locates a qpdf binary on PATH.
+func NewQpdfCompressor() (*QpdfCompressor, error) {
+	bin, err := lookPathAny(qpdfCandidates())
+	if err != nil {
+		return nil, fmt.Errorf("qpdf: %w", err)
+	}
+	return &QpdfCompressor{bin: bin}, nil
+}
+
+func (c *QpdfCompressor) Name() string { return "qpdf" }
+
+func (c *QpdfCompressor) Compress(input, output string, quality Quality) CompressResult {
+	os.MkdirAll(filepath.Dir(output), os.ModePerm)
+	args := []string{"--compress-streams=y", "--object-streams=generate"}
+	if quality == QualityScreen {
+		args = append(args, "--recompress-flate")
+	}
+	args = append(args, input, output)
+	origInfo, err := os.Stat(input)
+	if err != nil {
+		return CompressResult{Path: input, Err: err}
+	}
+	stderr, exitCode, runErr := runBackend(c.bin, args)
+	if runErr != nil {
+		return CompressResult{Path: input, ExitCode: exitCode, Stderr: stderr, Err: runErr}
+	}
+	compInfo, err := os.Stat(output)
+	if err != nil {
+		return CompressResult{Path: input, Err: err}
+	}
+	return CompressResult{OK: true, OrigSize: origInfo.Size(), CompSize: compInfo.Size(), Path: input}
+}
+
+// MutoolCompressor drives MuPDF's mutool clean, which strips unused
+// objects and recompresses streams. Like qpdf it has no image-quality
+// knob, so it is offered mainly as a lightweight fallback when neither
+// Ghostscript nor qpdf is installed.
+type MutoolCompressor struct {
+	bin string
+}
+
+func mutoolCandidates() []string {
+	if runtime.GOOS == "windows" {
+		return []string{"mutool.exe", "mutool"}
+	}
+	return []string{"mutool"}
+}
+
+// NewMutoolCompressor locates a mutool binary on PATH.
+func NewMutoolCompressor() (*MutoolCompressor, error) {
+	bin, err := lookPathAny(mutoolCandidates())
+	if err != nil {
+		return nil, fmt.Errorf("mutool: %w", err)
+	}
+	return &MutoolCompressor{bin: bin}, nil
+}
+
+func (c *MutoolCompressor) Name() string { return "mutool" }
+
+func (c *MutoolCompressor) Compress(input, output string, quality Quality) CompressResult {
+	os.MkdirAll(filepath.Dir(output), os.ModePerm)
+	origInfo, err := os.Stat(input)
+	if err != nil {
+		return CompressResult{Path: input, Err: err}
+	}
+	stderr, exitCode, runErr := runBackend(c.bin, []string{"clean", "-gggg", input, output})
+	if runErr != nil {
+		return CompressResult{Path: input, ExitCode: exitCode, Stderr: stderr, Err: runErr}
+	}
+	compInfo, err := os.Stat(output)
+	if err != nil {
+		return CompressResult{Path: input, Err: err}
+	}
+	return CompressResult{OK: true, OrigSize: origInfo.Size(), CompSize: compInfo.Size(), Path: input}
+}
+
+// backendFactories maps -backend names to constructors, in the order they
+// are tried by NewCompressor("auto").
+var backendFactories = []struct {
+	name string
+	new  func() (Compressor, error)
+}{
+	{"ghostscript", func() (Compressor, error) { return NewGhostscriptCompressor() }},
+	{"qpdf", func() (Compressor, error) { return NewQpdfCompressor() }},
+	{"mutool", func() (Compressor, error) { return NewMutoolCompressor() }},
+}
+
+// NewCompressor resolves the -backend flag value into a concrete
+// Compressor. "auto" (the default) probes each known backend in order and
+// uses the first one found on PATH.
+func NewCompressor(backend string) (Compressor, error) {
+	if backend != "" && backend != "auto" {
+		for _, f := range backendFactories {
+			if f.name == backend {
+				return f.new()
+			}
+		}
+		return nil, fmt.Errorf("unknown backend %q (want one of ghostscript, qpdf, mutool, auto)", backend)
+	}
+	var errs []error
+	for _, f := range backendFactories {
+		c, err := f.new()
+		if err == nil {
+			return c, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("no PDF compression backend found on PATH: %v", errs)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLookPathAnyFindsFirstAvailableCandidate(t *testing.T) {
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "fakebin")
+	if err := os.WriteFile(fake, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path, err := lookPathAny([]string{"definitely-not-a-real-binary-xyz", "fakebin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "fakebin" {
+		t.Fatalf("expected to resolve to fakebin, got %q", path)
+	}
+}
+
+func TestLookPathAnyReturnsErrorWhenNoneFound(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	_, err := lookPathAny([]string{"definitely-not-a-real-binary-xyz", "also-not-a-real-binary-abc"})
+	if err == nil {
+		t.Fatal("expected an error when none of the candidates are on PATH")
+	}
+}
+
+func TestNewCompressorRejectsUnknownBackend(t *testing.T) {
+	_, err := NewCompressor("not-a-real-backend")
+	if err == nil {
+		t.Fatal("expected an error for an unknown -backend value")
+	}
+}
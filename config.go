@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config drives non-interactive (-config) runs: everything that
+// runInteractive would otherwise prompt for, plus batch-only knobs that
+// have no interactive equivalent.
+type Config struct {
+	InputDir    string
+	OutputDir   string
+	Quality     Quality
+	Backend     string
+	Concurrency int
+	Include     []string
+	Exclude     []string
+	LogFile     string
+}
+
+// LoadConfig reads a simple "key = value" INI-style file (one setting per
+// line, "#" or ";" starts a comment, blank lines ignored). Section headers
+// are not supported; the format is intentionally flat since the tool has
+// no nested settings.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	defer f.Close()
+
+	cfg := &Config{
+		Quality:     QualityEbook,
+		Backend:     "auto",
+		Concurrency: 1,
+	}
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("config: %s:%d: expected key=value, got %q", path, lineNo, line)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "input_dir":
+			cfg.InputDir = value
+		case "output_dir":
+			cfg.OutputDir = value
+		case "quality":
+			cfg.Quality = Quality(value)
+		case "backend":
+			cfg.Backend = value
+		case "concurrency":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("config: %s:%d: invalid concurrency %q: %w", path, lineNo, value, err)
+			}
+			cfg.Concurrency = n
+		case "include":
+			cfg.Include = splitPatterns(value)
+		case "exclude":
+			cfg.Exclude = splitPatterns(value)
+		case "log_file":
+			cfg.LogFile = value
+		default:
+			return nil, fmt.Errorf("config: %s:%d: unknown key %q", path, lineNo, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	if cfg.InputDir == "" || cfg.OutputDir == "" {
+		return nil, fmt.Errorf("config: input_dir and output_dir are required")
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = 1
+	}
+	return cfg, nil
+}
+
+// splitPatterns turns a comma-separated glob list ("*.pdf, draft_*") into
+// its trimmed elements.
+func splitPatterns(value string) []string {
+	var patterns []string
+	for _, p := range strings.Split(value, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// matchesFilters reports whether relPath should be processed given the
+// config's include/exclude glob lists. An empty include list matches
+// everything; exclude always wins over include.
+func matchesFilters(relPath string, include, exclude []string) bool {
+	base := filepath.Base(relPath)
+	matchAny := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, base); ok {
+				return true
+			}
+			if ok, _ := filepath.Match(pattern, relPath); ok {
+				return true
+			}
+		}
+		return false
+	}
+	if matchAny(exclude) {
+		return false
+	}
+	if len(include) == 0 {
+		return true
+	}
+	return matchAny(include)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.ini")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigParsesKnownKeys(t *testing.T) {
+	path := writeConfig(t, `
+# comment
+input_dir = /in
+output_dir = /out
+quality = printer
+backend = qpdf
+concurrency = 4
+include = *.pdf, draft_*
+exclude = *_tmp.pdf
+log_file = /var/log/pdfcompressor.log
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.InputDir != "/in" || cfg.OutputDir != "/out" {
+		t.Fatalf("unexpected input/output dirs: %+v", cfg)
+	}
+	if cfg.Quality != QualityPrinter {
+		t.Fatalf("expected quality printer, got %q", cfg.Quality)
+	}
+	if cfg.Backend != "qpdf" {
+		t.Fatalf("expected backend qpdf, got %q", cfg.Backend)
+	}
+	if cfg.Concurrency != 4 {
+		t.Fatalf("expected concurrency 4, got %d", cfg.Concurrency)
+	}
+	if len(cfg.Include) != 2 || len(cfg.Exclude) != 1 {
+		t.Fatalf("unexpected include/exclude: %+v / %+v", cfg.Include, cfg.Exclude)
+	}
+	if cfg.LogFile != "/var/log/pdfcompressor.log" {
+		t.Fatalf("expected log_file to be captured, got %q", cfg.LogFile)
+	}
+}
+
+func TestLoadConfigRequiresInputAndOutputDir(t *testing.T) {
+	path := writeConfig(t, "quality = ebook\n")
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("expected error for missing input_dir/output_dir")
+	}
+}
+
+func TestLoadConfigRejectsUnknownKey(t *testing.T) {
+	path := writeConfig(t, "input_dir = /in\noutput_dir = /out\nbogus = 1\n")
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+}
+
+func TestMatchesFilters(t *testing.T) {
+	cases := []struct {
+		name             string
+		include, exclude []string
+		path             string
+		want             bool
+	}{
+		{"no filters matches everything", nil, nil, "a/b.pdf", true},
+		{"include matches base name", []string{"*.pdf"}, nil, "a/b.pdf", true},
+		{"include excludes non-matching", []string{"draft_*"}, nil, "a/b.pdf", false},
+		{"exclude wins over include", []string{"*.pdf"}, []string{"b.pdf"}, "a/b.pdf", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := matchesFilters(c.path, c.include, c.exclude); got != c.want {
+				t.Fatalf("matchesFilters(%q, %v, %v) = %v, want %v", c.path, c.include, c.exclude, got, c.want)
+			}
+		})
+	}
+}
+
+func TestResolveJobsConcurrencyPrecedence(t *testing.T) {
+	cfg := &Config{Concurrency: 1}
+
+	got := resolveJobs(RunOptions{Jobs: 8, JobsExplicit: false}, cfg)
+	if got != 1 {
+		t.Fatalf("expected config concurrency to win when -jobs wasn't passed, got %d", got)
+	}
+
+	got = resolveJobs(RunOptions{Jobs: 8, JobsExplicit: true}, cfg)
+	if got != 8 {
+		t.Fatalf("expected explicit -jobs to win over config concurrency, got %d", got)
+	}
+}
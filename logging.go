@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// newLogger builds the process-wide structured logger: JSON-handler when
+// -json is set (so operational logs stay machine-readable alongside the
+// ndjson FileResult stream), text-handler otherwise. Logs go to stderr by
+// default, leaving stdout free for -json's ndjson records, or are appended
+// to logFile when one is given (config's log_file key, for -config runs
+// with no interactive terminal to watch).
+func newLogger(jsonOutput bool, logFile string) (*slog.Logger, error) {
+	var w io.Writer = os.Stderr
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("log file: %w", err)
+		}
+		w = f
+	}
+	var handler slog.Handler
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if jsonOutput {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(handler), nil
+}
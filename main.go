@@ -2,25 +2,16 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
-	"sync"
 	"time"
 )
 
-type Stats struct {
-	Total          int
-	Success        int
-	Failed         int
-	OriginalSize   int64
-	CompressedSize int64
-	Errors         []string
-	mu             sync.Mutex
-}
-
 func promptDirectory(prompt string) string {
 	reader := bufio.NewReader(os.Stdin)
 	for {
@@ -35,7 +26,24 @@ func promptDirectory(prompt string) string {
 	}
 }
 
-func promptQuality() string {
+// promptInputPath is like promptDirectory but also accepts a path to a
+// supported archive (.zip, .cbz, .tar.gz, .tar.bz2), which OpenSource will
+// transparently unpack.
+func promptInputPath(prompt string) string {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print(prompt + ": ")
+		path, _ := reader.ReadString('\n')
+		path = strings.TrimSpace(path)
+		info, err := os.Stat(path)
+		if err == nil && (info.IsDir() || DetectArchiveKind(path) != archiveNone) {
+			return path
+		}
+		fmt.Println("❌ Путь не найден (ожидается папка либо .zip/.cbz/.tar.gz/.tar.bz2):", path, "\nПопробуйте снова.\n")
+	}
+}
+
+func promptQuality() Quality {
 	fmt.Println("Выберите уровень качества сжатия:")
 	fmt.Println("1. screen  (низкое качество, высокая компрессия)")
 	fmt.Println("2. ebook   (среднее качество, компромисс)")
@@ -48,17 +56,17 @@ func promptQuality() string {
 	choice = strings.TrimSpace(choice)
 	switch choice {
 	case "1":
-		return "/screen"
+		return QualityScreen
 	case "2", "":
-		return "/ebook"
+		return QualityEbook
 	case "3":
-		return "/printer"
+		return QualityPrinter
 	case "4":
-		return "/prepress"
+		return QualityPrepress
 	case "5":
-		return "/default"
+		return QualityDefault
 	default:
-		return "/ebook"
+		return QualityEbook
 	}
 }
 
@@ -76,103 +84,227 @@ func findAllPDFs(root string) ([]string, error) {
 	return files, err
 }
 
-func compressPDF(input, output, quality string) (bool, int64, int64, string) {
-	os.MkdirAll(filepath.Dir(output), os.ModePerm)
-	gsCmd := []string{
-		"-sDEVICE=pdfwrite",
-		"-dCompatibilityLevel=1.4",
-		fmt.Sprintf("-dPDFSETTINGS=%s", quality),
-		"-dNOPAUSE",
-		"-dQUIET",
-		"-dBATCH",
-		fmt.Sprintf("-sOutputFile=%s", output),
-		input,
-	}
-	origInfo, err := os.Stat(input)
+// runInteractive drives the original bufio-prompt flow: ask for input/
+// output directories and a quality preset, then compress everything found.
+func runInteractive(compressor Compressor, opts RunOptions) {
+	if !opts.JSONOutput {
+		fmt.Println("📁 Утилита пакетного сжатия PDF (Go)\n")
+		fmt.Printf("✔ Используется backend: %s\n\n", compressor.Name())
+	}
+
+	inputPath := promptInputPath("Введите путь к ВХОДНОЙ директории или архиву (.zip/.cbz/.tar.gz/.tar.bz2)")
+	outputPath := promptDirectory("Введите путь к ВЫХОДНОЙ директории")
+	opts.Quality = promptQuality()
+	start := time.Now()
+
+	src, err := OpenSource(inputPath)
 	if err != nil {
-		return false, 0, 0, input
+		fmt.Println("❌", err)
+		return
 	}
-	cmd := exec.Command("gswin64c", gsCmd...)
-	err = cmd.Run()
+	defer src.Close()
+
+	sink, err := OpenSink(outputPath, src, inputPath)
 	if err != nil {
-		return false, 0, 0, input
+		fmt.Println("❌", err)
+		return
 	}
-	compInfo, err := os.Stat(output)
+
+	pdfFiles, err := findAllPDFs(src.Dir)
 	if err != nil {
-		return false, 0, 0, input
+		fmt.Println("Ошибка поиска PDF:", err)
+		return
+	}
+	if !opts.JSONOutput {
+		fmt.Printf("\n🔍 Найдено PDF-файлов: %d\n\n", len(pdfFiles))
+	}
+
+	stats := processFiles(compressor, pdfFiles, src.Dir, sink.Dir, stateDirFor(src, inputPath, sink.Dir), opts)
+	printSummary(stats, time.Since(start), opts.JSONOutput)
+
+	if err := sink.Finalize(); err != nil {
+		fmt.Println("❌ Ошибка упаковки результата в архив:", err)
+	}
+	writeReportOrWarn(opts.Logger, compressor.Name(), inputPath, outputPath, stats, time.Since(start))
+
+	if !opts.JSONOutput {
+		fmt.Println("👋 Спасибо за использование утилиты!")
+		fmt.Println("\nНажмите Enter для выхода...")
+		bufio.NewReader(os.Stdin).ReadBytes('\n')
 	}
-	return true, origInfo.Size(), compInfo.Size(), input
 }
 
-func checkGhostscript() {
-	cmd := exec.Command("gswin64c", "--version")
-	out, err := cmd.CombinedOutput()
+// runBatch drives the non-interactive -config flow: no prompts, no "press
+// Enter to exit", settings come entirely from cfg. It reuses the same
+// processFiles/printSummary core as runInteractive so results are
+// identical in shape.
+func runBatch(compressor Compressor, cfg *Config, opts RunOptions) {
+	start := time.Now()
+	opts.Quality = cfg.Quality
+	opts.Jobs = resolveJobs(opts, cfg)
+
+	src, err := OpenSource(cfg.InputDir)
 	if err != nil {
-		fmt.Println("❌ Ghostscript не найден. Убедитесь, что он установлен и добавлен в PATH.")
+		fmt.Println("❌", err)
 		os.Exit(1)
 	}
-	fmt.Printf("✔ Ghostscript найден. Версия: %s\n\n", strings.TrimSpace(string(out)))
-}
+	defer src.Close()
 
-func main() {
-	fmt.Println("📁 Утилита пакетного сжатия PDF через Ghostscript (Go)\n")
-	checkGhostscript()
-	inputRoot := promptDirectory("Введите путь к ВХОДНОЙ директории")
-	outputRoot := promptDirectory("Введите путь к ВЫХОДНОЙ директории")
-	quality := promptQuality()
-	start := time.Now()
+	sink, err := OpenSink(cfg.OutputDir, src, cfg.InputDir)
+	if err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
 
-	pdfFiles, err := findAllPDFs(inputRoot)
+	allFiles, err := findAllPDFs(src.Dir)
 	if err != nil {
 		fmt.Println("Ошибка поиска PDF:", err)
+		os.Exit(1)
+	}
+
+	var pdfFiles []string
+	for _, input := range allFiles {
+		rel, _ := filepath.Rel(src.Dir, input)
+		if matchesFilters(rel, cfg.Include, cfg.Exclude) {
+			pdfFiles = append(pdfFiles, input)
+		}
+	}
+
+	stats := processFiles(compressor, pdfFiles, src.Dir, sink.Dir, stateDirFor(src, cfg.InputDir, sink.Dir), opts)
+	printSummary(stats, time.Since(start), opts.JSONOutput)
+
+	finalizeErr := sink.Finalize()
+	writeReportOrWarn(opts.Logger, compressor.Name(), cfg.InputDir, cfg.OutputDir, stats, time.Since(start))
+	if finalizeErr != nil {
+		fmt.Println("❌ Ошибка упаковки результата в архив:", finalizeErr)
+		os.Exit(1)
+	}
+
+	if stats.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runRetry re-processes only the Failed entries from a previous run's
+// report.json, reusing the same input/output roots it recorded.
+func runRetry(retryPath string, opts RunOptions) {
+	report, err := LoadReport(retryPath)
+	if err != nil {
+		opts.Logger.Error(err.Error())
+		os.Exit(1)
+	}
+	if len(report.Failed) == 0 {
+		fmt.Println("✔ В отчёте нет файлов с ошибками для повтора.")
 		return
 	}
-	stats := &Stats{Total: len(pdfFiles)}
-	fmt.Printf("\n🔍 Найдено PDF-файлов: %d\n\n", stats.Total)
-
-	var wg sync.WaitGroup
-	for _, input := range pdfFiles {
-		wg.Add(1)
-		go func(input string) {
-			defer wg.Done()
-			rel, _ := filepath.Rel(inputRoot, input)
-			output := filepath.Join(outputRoot, rel)
-			ok, orig, comp, path := compressPDF(input, output, quality)
-			stats.mu.Lock()
-			defer stats.mu.Unlock()
-			if ok {
-				stats.Success++
-				stats.OriginalSize += orig
-				stats.CompressedSize += comp
-			} else {
-				stats.Failed++
-				stats.Errors = append(stats.Errors, path)
-			}
-		}(input)
-	}
-	wg.Wait()
-
-	fmt.Println("\n========================================")
-	fmt.Println("📊 Сводка:")
-	fmt.Printf("Всего обработано: %d\n", stats.Total)
-	fmt.Printf("Успешно:          %d\n", stats.Success)
-	fmt.Printf("С ошибками:       %d\n", stats.Failed)
-	if stats.Total > 0 {
-		saved := stats.OriginalSize - stats.CompressedSize
-		ratio := float64(saved) / float64(stats.OriginalSize) * 100
-		fmt.Printf("\n📉 Общий размер до:   %.2f MB\n", float64(stats.OriginalSize)/(1024*1024))
-		fmt.Printf("📦 Общий размер после: %.2f MB\n", float64(stats.CompressedSize)/(1024*1024))
-		fmt.Printf("💾 Экономия: %.2f MB (%.1f%%)\n", float64(saved)/(1024*1024), ratio)
+
+	compressor, err := NewCompressor(report.Backend)
+	if err != nil {
+		opts.Logger.Error(err.Error())
+		os.Exit(1)
+	}
+
+	var pdfFiles []string
+	for _, item := range report.Failed {
+		pdfFiles = append(pdfFiles, filepath.Join(report.InputRoot, item.Path))
 	}
+
+	start := time.Now()
+	stats := processFiles(compressor, pdfFiles, report.InputRoot, report.OutputRoot, report.OutputRoot, opts)
+	printSummary(stats, time.Since(start), opts.JSONOutput)
+	writeReportOrWarn(opts.Logger, report.Backend, report.InputRoot, report.OutputRoot, stats, time.Since(start))
+
 	if stats.Failed > 0 {
-		fmt.Println("\n🚫 Ошибки в файлах:")
-		for _, err := range stats.Errors {
-			fmt.Println("  -", err)
+		os.Exit(1)
+	}
+}
+
+// resolveJobs decides the worker-pool size for a -config run: an explicit
+// -jobs on the command line always wins, since it's the more specific
+// choice; otherwise the config file's concurrency setting applies.
+func resolveJobs(opts RunOptions, cfg *Config) int {
+	if opts.JobsExplicit {
+		return opts.Jobs
+	}
+	return cfg.Concurrency
+}
+
+// stateDirFor picks where processFiles should keep its resume cache:
+// outputRoot itself for a plain directory input, or a stable directory
+// keyed to the archive for an archive input, since outputRoot is then an
+// ephemeral staging dir that Sink.Finalize deletes.
+func stateDirFor(src *Source, inputPath, outputRoot string) string {
+	if src.Kind == archiveNone {
+		return outputRoot
+	}
+	return archiveStateDir(inputPath)
+}
+
+// writeReportOrWarn builds and writes report.json/report.txt, logging
+// (rather than failing the run) if that write itself doesn't succeed.
+func writeReportOrWarn(logger *slog.Logger, backend, inputRoot, outputRoot string, stats *Stats, elapsed time.Duration) {
+	report := buildReport(backend, inputRoot, outputRoot, stats, elapsed)
+	if err := WriteReport(report, outputRoot); err != nil {
+		logger.Warn("не удалось записать report.json/report.txt", "error", err)
+	}
+}
+
+func main() {
+	backend := flag.String("backend", "auto", "backend компрессии: auto, ghostscript, qpdf, mutool")
+	configPath := flag.String("config", "", "путь к конфигурационному файлу для неинтерактивного запуска")
+	jsonOutput := flag.Bool("json", false, "выводить результаты построчно в формате JSON (ndjson), для CI/скриптов")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "количество одновременно запущенных процессов сжатия")
+	force := flag.Bool("force", false, "игнорировать кеш .pdfcompressor-state.json и пересжать всё заново")
+	minSavings := flag.Float64("min-savings", defaultMinSavings, "минимальная доля экономии (0-1), иначе исходный файл передаётся без изменений")
+	retryPath := flag.String("retry", "", "путь к report.json: повторно обработать только файлы с ошибками")
+	flag.Parse()
+
+	jobsExplicit := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "jobs" {
+			jobsExplicit = true
+		}
+	})
+
+	logger, err := newLogger(*jsonOutput, "")
+	if err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
+	}
+	opts := RunOptions{Jobs: *jobs, JobsExplicit: jobsExplicit, Force: *force, JSONOutput: *jsonOutput, MinSavings: *minSavings, Logger: logger}
+
+	if *retryPath != "" {
+		runRetry(*retryPath, opts)
+		return
+	}
+
+	if *configPath != "" {
+		cfg, err := LoadConfig(*configPath)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
+		}
+		if cfg.LogFile != "" {
+			fileLogger, err := newLogger(*jsonOutput, cfg.LogFile)
+			if err != nil {
+				fmt.Println("❌", err)
+				os.Exit(1)
+			}
+			opts.Logger = fileLogger
+		}
+		compressor, err := NewCompressor(cfg.Backend)
+		if err != nil {
+			fmt.Println("❌", err)
+			os.Exit(1)
 		}
+		runBatch(compressor, cfg, opts)
+		return
+	}
+
+	compressor, err := NewCompressor(*backend)
+	if err != nil {
+		fmt.Println("❌", err)
+		os.Exit(1)
 	}
-	fmt.Println("========================================\n")
-	fmt.Printf("⏱ Время выполнения: %s\n", time.Since(start))
-	fmt.Println("👋 Спасибо за использование утилиты!")
-	fmt.Println("\nНажмите Enter для выхода...")
-	bufio.NewReader(os.Stdin).ReadBytes('\n')
+	runInteractive(compressor, opts)
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrorCategory classifies why a file ended up in a report's Failed or
+// PassedThrough list, so a retry command or a monitoring dashboard can
+// group by cause instead of parsing free-text messages.
+type ErrorCategory string
+
+const (
+	CategoryBackendCrash     ErrorCategory = "gs_crash"
+	CategoryOutputLarger     ErrorCategory = "output_larger"
+	CategoryValidationFailed ErrorCategory = "validation_failed"
+	CategoryIOError          ErrorCategory = "io_error"
+)
+
+// FailedItem is one entry in a report's Failed or PassedThrough list.
+type FailedItem struct {
+	Path      string        `json:"path"`
+	Category  ErrorCategory `json:"category"`
+	ExitCode  int           `json:"exit_code,omitempty"`
+	Stderr    string        `json:"stderr,omitempty"`
+	ElapsedMs int64         `json:"elapsed_ms"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Report is the -retry-able artifact written as report.json (and mirrored
+// as human-readable report.txt) into the output directory at the end of
+// a run.
+type Report struct {
+	Backend        string       `json:"backend"`
+	InputRoot      string       `json:"input_root"`
+	OutputRoot     string       `json:"output_root"`
+	Total          int          `json:"total"`
+	Recompressed   int          `json:"recompressed"`
+	PassedThrough  int          `json:"passed_through"`
+	Skipped        int          `json:"skipped"`
+	Failed         []FailedItem `json:"failed"`
+	PassedItems    []FailedItem `json:"passed_through_items"`
+	OriginalSize   int64        `json:"original_size"`
+	CompressedSize int64        `json:"compressed_size"`
+	DurationMs     int64        `json:"duration_ms"`
+}
+
+// WriteReport writes both report.json and report.txt into outputRoot.
+func WriteReport(report *Report, outputRoot string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(outputRoot, "report.json"), data, 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputRoot, "report.txt"), []byte(renderReportText(report)), 0644)
+}
+
+func renderReportText(report *Report) string {
+	text := fmt.Sprintf(
+		"Backend: %s\nВсего: %d  Пересжато: %d  Без изменений: %d  Пропущено: %d  Ошибок: %d\n",
+		report.Backend, report.Total, report.Recompressed, report.PassedThrough, report.Skipped, len(report.Failed),
+	)
+	if len(report.Failed) > 0 {
+		text += "\nОшибки:\n"
+		for _, item := range report.Failed {
+			text += fmt.Sprintf("  - [%s] %s: %s\n", item.Category, item.Path, item.Error)
+		}
+	}
+	if len(report.PassedItems) > 0 {
+		text += "\nБез изменений:\n"
+		for _, item := range report.PassedItems {
+			text += fmt.Sprintf("  - [%s] %s\n", item.Category, item.Path)
+		}
+	}
+	return text
+}
+
+// LoadReport reads a report.json written by a previous run, for -retry.
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("retry: %w", err)
+	}
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("retry: %w", err)
+	}
+	return &report, nil
+}
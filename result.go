@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FileResult is the -json record emitted for each processed PDF, one JSON
+// object per line (ndjson) so the tool can be piped into other services.
+type FileResult struct {
+	Path       string  `json:"path"`
+	OrigSize   int64   `json:"orig_size"`
+	CompSize   int64   `json:"comp_size"`
+	Ratio      float64 `json:"ratio"`
+	DurationMs int64   `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Summary is the final -json record, emitted once after every FileResult.
+type Summary struct {
+	Total          int     `json:"total"`
+	Recompressed   int     `json:"recompressed"`
+	PassedThrough  int     `json:"passed_through"`
+	Skipped        int     `json:"skipped"`
+	Failed         int     `json:"failed"`
+	OriginalSize   int64   `json:"original_size"`
+	CompressedSize int64   `json:"compressed_size"`
+	Ratio          float64 `json:"ratio"`
+	DurationMs     int64   `json:"duration_ms"`
+}
+
+// emitJSON writes v as a single ndjson line to stdout.
+func emitJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, "json encode error:", err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Source is a staged, walkable view of whatever the user pointed the tool
+// at: either the directory itself, or a temp directory holding every PDF
+// extracted from an archive. Callers always walk Dir with findAllPDFs and
+// must call Close when done.
+type Source struct {
+	Dir     string
+	Kind    ArchiveKind
+	cleanup func()
+}
+
+func (s *Source) Close() {
+	if s.cleanup != nil {
+		s.cleanup()
+	}
+}
+
+// OpenSource resolves path into a Source. A plain directory is used
+// in-place; a recognized archive (.zip, .cbz, .tar.gz, .tar.bz2) is
+// extracted into a temp directory first so the rest of the pipeline never
+// has to know the difference.
+func OpenSource(path string) (*Source, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	if info.IsDir() {
+		return &Source{Dir: path, Kind: archiveNone, cleanup: func() {}}, nil
+	}
+
+	kind := DetectArchiveKind(path)
+	if kind == archiveNone {
+		return nil, fmt.Errorf("source: %s is neither a directory nor a supported archive (.zip/.cbz/.tar.gz/.tar.bz2)", path)
+	}
+	tmp, err := os.MkdirTemp("", "pdfcompressor-src-*")
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	if err := ExtractArchive(path, kind, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return nil, err
+	}
+	return &Source{Dir: tmp, Kind: kind, cleanup: func() { os.RemoveAll(tmp) }}, nil
+}
+
+// Sink is where processFiles writes compressed output. For a plain
+// directory input it is simply that output directory; for an archive
+// input it stages results in a temp directory and, on Finalize, packs
+// them into a single archive matching the input's layout.
+type Sink struct {
+	Dir      string
+	finalize func() error
+}
+
+func (s *Sink) Finalize() error { return s.finalize() }
+
+// OpenSink prepares where compressed results for src should land.
+// outputPath is the user-supplied output directory; when src came from an
+// archive, the packed result is written inside outputPath under a name
+// derived from the original archive (tar.bz2 becomes tar.gz, since Go's
+// standard library can only read bzip2, not write it).
+func OpenSink(outputPath string, src *Source, origInputPath string) (*Sink, error) {
+	if src.Kind == archiveNone {
+		return &Sink{Dir: outputPath, finalize: func() error { return nil }}, nil
+	}
+	tmp, err := os.MkdirTemp("", "pdfcompressor-dst-*")
+	if err != nil {
+		return nil, fmt.Errorf("sink: %w", err)
+	}
+	dest := filepath.Join(outputPath, archiveOutputName(origInputPath, src.Kind))
+	return &Sink{
+		Dir: tmp,
+		finalize: func() error {
+			defer os.RemoveAll(tmp)
+			return PackArchive(tmp, src.Kind, dest)
+		},
+	}, nil
+}
+
+// archiveStateDir returns a stable directory to keep the resume cache in
+// for an archive-mode run. processFiles normally keeps it in outputRoot,
+// but for archive inputs outputRoot is the temp staging dir OpenSink
+// creates and Finalize deletes, which would make every run behave like
+// -force and leave nothing to resume from after a crash. Keying it to the
+// archive's own path instead makes it survive Finalize and lets a later
+// run against the same archive pick up where the last one left off.
+func archiveStateDir(archivePath string) string {
+	abs, err := filepath.Abs(archivePath)
+	if err != nil {
+		abs = archivePath
+	}
+	dir := filepath.Dir(abs)
+	base := filepath.Base(abs)
+	return filepath.Join(dir, "."+base+".pdfcompressor-state")
+}
+
+// archiveOutputName derives the packed result's file name from the
+// original archive's name, remapping tar.bz2 to tar.gz since that is the
+// format PackArchive actually produces for it.
+func archiveOutputName(origInputPath string, kind ArchiveKind) string {
+	base := filepath.Base(origInputPath)
+	if kind == archiveTarBz2 {
+		base = strings.TrimSuffix(base, ".tbz2")
+		base = strings.TrimSuffix(base, ".tar.bz2")
+		base += ".tar.gz"
+	}
+	return base
+}
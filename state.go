@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateFileName is the resume cache written into the output root. Its
+// presence lets re-running the tool on the same input/output pair skip
+// files that were already compressed successfully (e.g. after a crash or
+// an interrupted batch).
+const stateFileName = ".pdfcompressor-state.json"
+
+// FileState records enough about a successfully compressed input to tell,
+// on a later run, whether it has changed since.
+type FileState struct {
+	SHA256  string    `json:"sha256"`
+	ModTime time.Time `json:"mtime"`
+}
+
+// State is the resumable record of a compression run, keyed by the input
+// file's path relative to the input root.
+type State struct {
+	Files map[string]FileState `json:"files"`
+}
+
+// LoadState reads the resume cache from outputRoot. A missing file yields
+// an empty, ready-to-use State rather than an error.
+func LoadState(outputRoot string) (*State, error) {
+	data, err := os.ReadFile(filepath.Join(outputRoot, stateFileName))
+	if os.IsNotExist(err) {
+		return &State{Files: map[string]FileState{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Files == nil {
+		s.Files = map[string]FileState{}
+	}
+	return &s, nil
+}
+
+// Save writes the resume cache into outputRoot, creating it if necessary.
+func (s *State) Save(outputRoot string) error {
+	if err := os.MkdirAll(outputRoot, os.ModePerm); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputRoot, stateFileName), data, 0644)
+}
+
+// UpToDate reports whether relPath was already compressed successfully by
+// a previous run and has not changed since (same mtime and SHA-256).
+func (s *State) UpToDate(relPath string, info os.FileInfo, hash string) bool {
+	prev, ok := s.Files[relPath]
+	if !ok {
+		return false
+	}
+	return prev.ModTime.Equal(info.ModTime()) && prev.SHA256 == hash
+}
+
+// Record marks relPath as successfully compressed.
+func (s *State) Record(relPath string, info os.FileInfo, hash string) {
+	s.Files[relPath] = FileState{SHA256: hash, ModTime: info.ModTime()}
+}
+
+// sha256File hashes a file's contents, used to detect input changes that a
+// stale mtime might miss (e.g. after a git checkout or archive extraction).
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
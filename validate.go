@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// validatePDF runs a lightweight, dependency-free sanity check on a
+// compressed output: it must start with the PDF magic header, end with
+// %%EOF, and carry a startxref whose offset parses as a number. This
+// catches the common ways a crashed or truncated Ghostscript run produces
+// a file that exists but is not actually a usable PDF.
+func validatePDF(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, 5)
+	if _, err := f.Read(header); err != nil || string(header) != "%PDF-" {
+		return fmt.Errorf("validate: %s does not start with %%PDF-", path)
+	}
+
+	tail, err := readTail(f, 1024)
+	if err != nil {
+		return fmt.Errorf("validate: %w", err)
+	}
+	trimmed := strings.TrimRight(tail, "\r\n\x00 \t")
+	if !strings.HasSuffix(trimmed, "%%EOF") {
+		return fmt.Errorf("validate: %s does not end with %%%%EOF", path)
+	}
+
+	idx := strings.LastIndex(tail, "startxref")
+	if idx < 0 {
+		return fmt.Errorf("validate: %s has no startxref", path)
+	}
+	scanner := bufio.NewScanner(strings.NewReader(tail[idx+len("startxref"):]))
+	scanner.Split(bufio.ScanWords)
+	if !scanner.Scan() {
+		return fmt.Errorf("validate: %s has a startxref with no offset", path)
+	}
+	if _, err := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 64); err != nil {
+		return fmt.Errorf("validate: %s has a non-numeric xref offset: %w", path, err)
+	}
+	return nil
+}
+
+// readTail returns up to n bytes from the end of an already-open file.
+func readTail(f *os.File, n int64) (string, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := info.Size()
+	if size < n {
+		n = size
+	}
+	buf := make([]byte, n)
+	if _, err := f.ReadAt(buf, size-n); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
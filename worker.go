@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RunOptions carries the worker-pool and resume knobs shared by
+// runInteractive and runBatch, on top of whatever entry-point-specific
+// setup picks the input/output directories and Quality.
+type RunOptions struct {
+	Quality Quality
+	Jobs    int
+	// JobsExplicit is true when -jobs was actually passed on the command
+	// line, as opposed to Jobs merely holding flag.Int's runtime.NumCPU()
+	// default. runBatch uses it to decide whether the config file's
+	// concurrency setting should win.
+	JobsExplicit bool
+	Force        bool
+	JSONOutput   bool
+	// MinSavings is the minimum fraction (0-1) the compressed file must be
+	// smaller than the original; anything less, or an output that fails
+	// validatePDF, is rejected and the original is passed through as-is.
+	MinSavings float64
+	Logger     *slog.Logger
+}
+
+// defaultMinSavings matches the request's -min-savings default: a
+// compressed file must be at least 5% smaller than the original or it is
+// considered not worth keeping.
+const defaultMinSavings = 0.05
+
+type Stats struct {
+	Total          int
+	Recompressed   int
+	PassedThrough  int
+	Skipped        int
+	Failed         int
+	OriginalSize   int64
+	CompressedSize int64
+	FailedItems    []FailedItem
+	PassedItems    []FailedItem
+	mu             sync.Mutex
+}
+
+// progressSnapshot is a consistent, lock-free copy of Stats for the
+// progress ticker to render.
+func (s *Stats) progressSnapshot() (done, total int, savedBytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done = s.Recompressed + s.PassedThrough + s.Skipped + s.Failed
+	return done, s.Total, s.OriginalSize - s.CompressedSize
+}
+
+// processFiles compresses every file in pdfFiles into outputRoot (mirroring
+// each file's path relative to inputRoot), using a bounded pool of
+// opts.Jobs workers and aggregating the result into a Stats. Files whose
+// SHA-256 and mtime match stateDir's resume cache are skipped unless
+// opts.Force is set. stateDir is usually outputRoot, but callers pass a
+// stable directory instead when outputRoot is an ephemeral staging dir
+// (archive-mode runs, which get deleted by Sink.Finalize). When
+// opts.JSONOutput is set, a FileResult ndjson line is emitted as each file
+// finishes. This is the shared core behind both runInteractive and
+// runBatch so the two entry points can't drift.
+func processFiles(compressor Compressor, pdfFiles []string, inputRoot, outputRoot, stateDir string, opts RunOptions) *Stats {
+	logger := opts.Logger
+	if logger == nil {
+		logger, _ = newLogger(opts.JSONOutput, "")
+	}
+
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	state, err := LoadState(stateDir)
+	if err != nil {
+		logger.Warn("не удалось прочитать кеш состояния", "error", err)
+		state = &State{Files: map[string]FileState{}}
+	}
+	var stateMu sync.Mutex
+
+	stats := &Stats{Total: len(pdfFiles)}
+	stop := startProgressTicker(stats, opts.JSONOutput)
+	defer stop()
+
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for _, input := range pdfFiles {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(input string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			rel, _ := filepath.Rel(inputRoot, input)
+			output := filepath.Join(outputRoot, rel)
+
+			fileStart := time.Now()
+			outcome := compressOne(compressor, state, &stateMu, input, rel, output, opts)
+			outcome.ElapsedMs = time.Since(fileStart).Milliseconds()
+			outcome.RelPath = rel
+
+			stats.mu.Lock()
+			switch {
+			case outcome.Skipped:
+				stats.Skipped++
+				stats.OriginalSize += outcome.OrigSize
+				stats.CompressedSize += outcome.CompSize
+			case outcome.OK && outcome.PassedThrough:
+				stats.PassedThrough++
+				stats.OriginalSize += outcome.OrigSize
+				stats.CompressedSize += outcome.CompSize
+				stats.PassedItems = append(stats.PassedItems, outcome.toFailedItem())
+			case outcome.OK:
+				stats.Recompressed++
+				stats.OriginalSize += outcome.OrigSize
+				stats.CompressedSize += outcome.CompSize
+			default:
+				stats.Failed++
+				stats.FailedItems = append(stats.FailedItems, outcome.toFailedItem())
+			}
+			stats.mu.Unlock()
+
+			if !outcome.OK {
+				logger.Error("сжатие не удалось", "path", input, "category", outcome.Category, "exit_code", outcome.ExitCode)
+			} else if outcome.PassedThrough {
+				logger.Info("файл передан без изменений", "path", input, "category", outcome.Category)
+			}
+
+			if opts.JSONOutput {
+				result := FileResult{
+					Path:       input,
+					OrigSize:   outcome.OrigSize,
+					CompSize:   outcome.CompSize,
+					DurationMs: outcome.ElapsedMs,
+				}
+				if outcome.OrigSize > 0 {
+					result.Ratio = float64(outcome.OrigSize-outcome.CompSize) / float64(outcome.OrigSize)
+				}
+				if !outcome.OK {
+					result.Error = outcome.ErrorText()
+				}
+				emitJSON(result)
+			}
+		}(input)
+	}
+	wg.Wait()
+
+	if err := state.Save(stateDir); err != nil {
+		logger.Warn("не удалось сохранить кеш состояния", "error", err)
+	}
+
+	return stats
+}
+
+// fileOutcome is the full result of processing one file: enough to
+// aggregate Stats, emit a FileResult, and build a FailedItem without
+// re-deriving anything.
+type fileOutcome struct {
+	CompressResult
+	// RelPath is the file's path relative to inputRoot. FailedItem/
+	// PassedItem entries store this (not CompressResult.Path, which is the
+	// full walked path) so a later -retry run can rejoin it against
+	// whatever InputRoot the report records without double-prefixing.
+	RelPath       string
+	Skipped       bool
+	PassedThrough bool
+	Category      ErrorCategory
+	ElapsedMs     int64
+}
+
+func (o fileOutcome) ErrorText() string {
+	if o.Err != nil {
+		return o.Err.Error()
+	}
+	return ""
+}
+
+func (o fileOutcome) toFailedItem() FailedItem {
+	return FailedItem{
+		Path:      o.RelPath,
+		Category:  o.Category,
+		ExitCode:  o.ExitCode,
+		Stderr:    o.Stderr,
+		ElapsedMs: o.ElapsedMs,
+		Error:     o.ErrorText(),
+	}
+}
+
+// compressOne resolves the resume cache for a single file, then either
+// skips it (cache hit, not -force) or runs the compressor, applies the
+// size-delta guard and integrity check, and records the new state on
+// success.
+func compressOne(compressor Compressor, state *State, stateMu *sync.Mutex, input, rel, output string, opts RunOptions) fileOutcome {
+	info, statErr := os.Stat(input)
+	if statErr != nil {
+		return fileOutcome{
+			CompressResult: CompressResult{Path: input, Err: statErr},
+			Category:       CategoryIOError,
+		}
+	}
+
+	if !opts.Force {
+		if hash, err := sha256File(input); err == nil {
+			stateMu.Lock()
+			upToDate := state.UpToDate(rel, info, hash)
+			stateMu.Unlock()
+			if upToDate {
+				if outInfo, err := os.Stat(output); err == nil {
+					return fileOutcome{
+						CompressResult: CompressResult{OK: true, OrigSize: info.Size(), CompSize: outInfo.Size(), Path: input},
+						Skipped:        true,
+					}
+				}
+			}
+		}
+	}
+
+	result := compressor.Compress(input, output, opts.Quality)
+	if !result.OK {
+		category := CategoryBackendCrash
+		if result.ExitCode == 0 && result.Err != nil {
+			category = CategoryIOError
+		}
+		return fileOutcome{CompressResult: result, Category: category}
+	}
+
+	accepted, finalCompSize, passedThrough, category := guardOutput(input, output, result.OrigSize, result.CompSize, opts.MinSavings)
+	result.CompSize = finalCompSize
+	if !accepted {
+		return fileOutcome{CompressResult: CompressResult{OK: false, Path: input, Err: fmt.Errorf("pass-through copy failed")}, Category: CategoryIOError}
+	}
+
+	if hash, err := sha256File(input); err == nil {
+		stateMu.Lock()
+		state.Record(rel, info, hash)
+		stateMu.Unlock()
+	}
+	return fileOutcome{CompressResult: result, PassedThrough: passedThrough, Category: category}
+}
+
+// guardOutput rejects a compressed output that either saved too little
+// space or failed the integrity check, replacing it with a copy of the
+// original input instead. It returns the (possibly revised) compressed
+// size, whether a pass-through happened, and why (for reporting).
+func guardOutput(input, output string, origSize, compSize int64, minSavings float64) (ok bool, finalCompSize int64, passedThrough bool, category ErrorCategory) {
+	invalid := validatePDF(output) != nil
+	notEnoughSavings := !invalid && origSize > 0 && float64(compSize) >= float64(origSize)*(1-minSavings)
+	if !notEnoughSavings && !invalid {
+		return true, compSize, false, ""
+	}
+	category = CategoryOutputLarger
+	if invalid {
+		category = CategoryValidationFailed
+	}
+	if err := copyFile(input, output); err != nil {
+		return false, compSize, false, category
+	}
+	return true, origSize, true, category
+}
+
+// copyFile overwrites dst with a byte-for-byte copy of src, used when the
+// size-delta guard or integrity check rejects a compressed output.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// startProgressTicker prints a periodically-updated "done/total" line to
+// stderr while a batch runs. It is silent in -json mode, where stdout is
+// reserved for ndjson records. Call the returned func to stop it.
+func startProgressTicker(stats *Stats, jsonOutput bool) func() {
+	if jsonOutput || stats.Total == 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				printProgress(stats, start)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+func printProgress(stats *Stats, start time.Time) {
+	completed, total, savedBytes := stats.progressSnapshot()
+	if completed == 0 {
+		return
+	}
+	elapsed := time.Since(start)
+	eta := elapsed / time.Duration(completed) * time.Duration(total-completed)
+	fmt.Printf("\r⏳ %d/%d файлов, сэкономлено %.1f MB, осталось ~%s   ",
+		completed, total, float64(savedBytes)/(1024*1024), eta.Round(time.Second))
+}
+
+// printSummary renders the final Stats either as a human-readable block
+// (interactive mode) or as a single Summary ndjson line (-json).
+func printSummary(stats *Stats, elapsed time.Duration, jsonOutput bool) {
+	if jsonOutput {
+		summary := Summary{
+			Total:          stats.Total,
+			Recompressed:   stats.Recompressed,
+			PassedThrough:  stats.PassedThrough,
+			Skipped:        stats.Skipped,
+			Failed:         stats.Failed,
+			OriginalSize:   stats.OriginalSize,
+			CompressedSize: stats.CompressedSize,
+			DurationMs:     elapsed.Milliseconds(),
+		}
+		if stats.OriginalSize > 0 {
+			summary.Ratio = float64(stats.OriginalSize-stats.CompressedSize) / float64(stats.OriginalSize)
+		}
+		emitJSON(summary)
+		return
+	}
+
+	fmt.Println("\n========================================")
+	fmt.Println("📊 Сводка:")
+	fmt.Printf("Всего обработано:   %d\n", stats.Total)
+	fmt.Printf("Пересжато:          %d\n", stats.Recompressed)
+	fmt.Printf("Без изменений:      %d (сжатие не дало выгоды или не прошло проверку)\n", stats.PassedThrough)
+	fmt.Printf("Пропущено (кеш):    %d\n", stats.Skipped)
+	fmt.Printf("С ошибками:         %d\n", stats.Failed)
+	if stats.Total > 0 {
+		saved := stats.OriginalSize - stats.CompressedSize
+		ratio := float64(saved) / float64(stats.OriginalSize) * 100
+		fmt.Printf("\n📉 Общий размер до:   %.2f MB\n", float64(stats.OriginalSize)/(1024*1024))
+		fmt.Printf("📦 Общий размер после: %.2f MB\n", float64(stats.CompressedSize)/(1024*1024))
+		fmt.Printf("💾 Экономия: %.2f MB (%.1f%%)\n", float64(saved)/(1024*1024), ratio)
+	}
+	if stats.Failed > 0 {
+		fmt.Println("\n🚫 Ошибки в файлах:")
+		for _, item := range stats.FailedItems {
+			fmt.Printf("  - [%s] %s: %s\n", item.Category, item.Path, item.Error)
+		}
+	}
+	fmt.Println("========================================\n")
+	fmt.Printf("⏱ Время выполнения: %s\n", elapsed)
+}
+
+// buildReport assembles the -retry-able Report from a finished Stats.
+func buildReport(backend, inputRoot, outputRoot string, stats *Stats, elapsed time.Duration) *Report {
+	report := &Report{
+		Backend:        backend,
+		InputRoot:      inputRoot,
+		OutputRoot:     outputRoot,
+		Total:          stats.Total,
+		Recompressed:   stats.Recompressed,
+		PassedThrough:  stats.PassedThrough,
+		Skipped:        stats.Skipped,
+		Failed:         stats.FailedItems,
+		PassedItems:    stats.PassedItems,
+		OriginalSize:   stats.OriginalSize,
+		CompressedSize: stats.CompressedSize,
+		DurationMs:     elapsed.Milliseconds(),
+	}
+	if report.Failed == nil {
+		report.Failed = []FailedItem{}
+	}
+	if report.PassedItems == nil {
+		report.PassedItems = []FailedItem{}
+	}
+	return report
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, content []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func validPDFBytes() []byte {
+	return []byte("%PDF-1.4\n1 0 obj\n<< >>\nendobj\nxref\n0 1\n0000000000 65535 f \ntrailer\n<< /Size 1 >>\nstartxref\n9\n%%EOF")
+}
+
+func TestGuardOutputAcceptsGoodSavings(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.pdf")
+	output := filepath.Join(dir, "out.pdf")
+	writeFile(t, input, make([]byte, 1000))
+	writeFile(t, output, validPDFBytes())
+
+	ok, finalSize, passedThrough, category := guardOutput(input, output, 1000, int64(len(validPDFBytes())), defaultMinSavings)
+	if !ok || passedThrough || category != "" {
+		t.Fatalf("expected a clean accept, got ok=%v passedThrough=%v category=%q", ok, passedThrough, category)
+	}
+	if finalSize != int64(len(validPDFBytes())) {
+		t.Fatalf("expected finalCompSize to be the compressed size, got %d", finalSize)
+	}
+}
+
+func TestGuardOutputRejectsInsufficientSavings(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.pdf")
+	output := filepath.Join(dir, "out.pdf")
+	origContent := make([]byte, 1000)
+	writeFile(t, input, origContent)
+	writeFile(t, output, validPDFBytes())
+
+	// Compressed size is 990 out of 1000: under 5% savings.
+	ok, finalSize, passedThrough, category := guardOutput(input, output, 1000, 990, defaultMinSavings)
+	if !ok || !passedThrough {
+		t.Fatalf("expected a pass-through accept, got ok=%v passedThrough=%v", ok, passedThrough)
+	}
+	if category != CategoryOutputLarger {
+		t.Fatalf("expected CategoryOutputLarger, got %q", category)
+	}
+	if finalSize != 1000 {
+		t.Fatalf("expected finalCompSize to fall back to origSize, got %d", finalSize)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(origContent) {
+		t.Fatal("expected output to be overwritten with a copy of the original input")
+	}
+}
+
+func TestGuardOutputRejectsInvalidPDF(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.pdf")
+	output := filepath.Join(dir, "out.pdf")
+	writeFile(t, input, make([]byte, 1000))
+	writeFile(t, output, []byte("not a pdf at all"))
+
+	ok, _, passedThrough, category := guardOutput(input, output, 1000, 10, defaultMinSavings)
+	if !ok || !passedThrough {
+		t.Fatalf("expected a pass-through accept despite the invalid output, got ok=%v passedThrough=%v", ok, passedThrough)
+	}
+	if category != CategoryValidationFailed {
+		t.Fatalf("expected CategoryValidationFailed, got %q", category)
+	}
+}